@@ -0,0 +1,173 @@
+package zeroconf
+
+import "net"
+
+// merge folds an incoming record into the cache, computing which lifecycle
+// event occurred (Added/Updated/Refreshed/Removed), notifies any matching
+// Subscribe channels, and returns the merged entry. A single mDNS packet
+// rarely carries every field of an instance (a PTR-only re-announce, or
+// SRV/TXT/A split across packets are both routine), so fields the incoming
+// record leaves zero are first backfilled from the existing entry rather
+// than blanking out a previously-complete sighting.
+func (c *entryCache) merge(incoming *ServiceEntry) *ServiceEntry {
+	key := incoming.ServiceInstanceName()
+
+	c.Lock()
+	defer c.Unlock()
+
+	existing, known := c.entries[key]
+	if known {
+		fillMissingFields(incoming, existing)
+	}
+
+	switch {
+	case incoming.TTL == 0:
+		incoming.eventType = Removed
+	case !known:
+		incoming.eventType = Added
+	case entryChanged(existing, incoming):
+		incoming.eventType = Updated
+	default:
+		incoming.eventType = Refreshed
+	}
+
+	if incoming.eventType == Removed {
+		delete(c.entries, key)
+	} else {
+		c.entries[key] = incoming
+	}
+
+	for _, sub := range c.subscribers[incoming.ServiceName()] {
+		select {
+		case sub <- *incoming:
+		default:
+		}
+	}
+
+	return incoming
+}
+
+// fillMissingFields copies prior's Port/HostName/Text/addrs onto incoming
+// wherever incoming left them at their zero value, so a partial record
+// doesn't erase data only a previous, more complete sighting supplied.
+func fillMissingFields(incoming, prior *ServiceEntry) {
+	if incoming.Port == 0 {
+		incoming.Port = prior.Port
+	}
+	if incoming.HostName == "" {
+		incoming.HostName = prior.HostName
+	}
+	if len(incoming.Text) == 0 {
+		incoming.Text = prior.Text
+	}
+	if len(incoming.AddrIPv4) == 0 {
+		incoming.AddrIPv4 = prior.AddrIPv4
+	}
+	if len(incoming.AddrIPv6) == 0 {
+		incoming.AddrIPv6 = prior.AddrIPv6
+	}
+}
+
+// entryChanged reports whether b differs from a in any way a consumer would
+// care about, as opposed to merely carrying a bumped refreshTime/expiryTime.
+func entryChanged(a, b *ServiceEntry) bool {
+	if a.Port != b.Port || a.HostName != b.HostName {
+		return true
+	}
+	if !stringSliceEqual(a.Text, b.Text) {
+		return true
+	}
+	if !ipSliceEqual(a.AddrIPv4, b.AddrIPv4) || !ipSliceEqual(a.AddrIPv6, b.AddrIPv6) {
+		return true
+	}
+	return false
+}
+
+func stringSliceEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func ipSliceEqual(a, b []net.IP) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Subscribe returns a channel of ServiceEntry events for service (a
+// "_service._proto." name, domain-less — it is resolved against "local"
+// the same way ServiceTypeName does) without the caller needing to run a
+// separate LookupParams/Browse. Instances already known to the cache are
+// delivered immediately, followed by future Added/Updated/Refreshed/Removed
+// events as they are merged in. Call Unsubscribe with the same service name
+// and the returned channel once done to release it.
+func (b *Browser) Subscribe(service string) <-chan ServiceEntry {
+	out := make(chan ServiceEntry, 16)
+	if b.cache == nil {
+		return out
+	}
+
+	name := NewServiceRecord("", service, "local").ServiceName()
+
+	b.cache.Lock()
+	var initial []ServiceEntry
+	for _, e := range b.cache.entries {
+		if e.ServiceName() == name {
+			initial = append(initial, *e)
+		}
+	}
+	if b.cache.subscribers == nil {
+		b.cache.subscribers = make(map[string][]chan ServiceEntry)
+	}
+	b.cache.subscribers[name] = append(b.cache.subscribers[name], out)
+	b.cache.Unlock()
+
+	// Deliver the initial snapshot from a goroutine so a subscriber with
+	// more already-cached instances than out's buffer can hold doesn't
+	// deadlock merge(), which sends to every subscriber while holding the
+	// same cache lock this snapshot was read under.
+	go func() {
+		for _, e := range initial {
+			out <- e
+		}
+	}()
+
+	return out
+}
+
+// Unsubscribe stops delivering events for service on ch, closing it. ch
+// must be a channel previously returned by Subscribe for the same service
+// name.
+func (b *Browser) Unsubscribe(service string, ch <-chan ServiceEntry) {
+	if b.cache == nil {
+		return
+	}
+
+	name := NewServiceRecord("", service, "local").ServiceName()
+
+	b.cache.Lock()
+	defer b.cache.Unlock()
+
+	subs := b.cache.subscribers[name]
+	for i, sub := range subs {
+		if (<-chan ServiceEntry)(sub) != ch {
+			continue
+		}
+		b.cache.subscribers[name] = append(subs[:i], subs[i+1:]...)
+		close(sub)
+		return
+	}
+}
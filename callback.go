@@ -0,0 +1,111 @@
+package zeroconf
+
+import (
+	"context"
+	"sync"
+)
+
+// EntryCallback is invoked for every ServiceEntry event observed while
+// browsing or resolving a service.
+type EntryCallback func(entry *ServiceEntry, event ServiceEventType)
+
+// callbackWorkers bounds the number of goroutines used to fan callback
+// invocations out from the packet reader, so a slow callback can stall at
+// most one of them instead of the reader itself.
+const callbackWorkers = 8
+
+// callbackDispatcher multiplexes entries arriving on a channel across a
+// fixed pool of reusable goroutines, invoking fn for each one. If every
+// worker is still busy with a slow fn, feed drops the entry instead of
+// blocking, so that backpressure doesn't propagate all the way back to the
+// packet reader feeding Entries — except for Removed, which is never
+// dropped: a consumer relies on it to invalidate a dead node (e.g. evict it
+// from a connection pool or registry), and a silently dropped goodbye would
+// leave that node looking alive forever.
+type callbackDispatcher struct {
+	entries chan *ServiceEntry
+	fn      EntryCallback
+	wg      sync.WaitGroup
+}
+
+func newCallbackDispatcher(fn EntryCallback) *callbackDispatcher {
+	d := &callbackDispatcher{
+		entries: make(chan *ServiceEntry, callbackWorkers),
+		fn:      fn,
+	}
+	d.wg.Add(callbackWorkers)
+	for i := 0; i < callbackWorkers; i++ {
+		go d.worker()
+	}
+
+	return d
+}
+
+func (d *callbackDispatcher) worker() {
+	defer d.wg.Done()
+	for entry := range d.entries {
+		d.fn(entry, entry.EventType())
+	}
+}
+
+func (d *callbackDispatcher) feed(entries <-chan *ServiceEntry) {
+	for entry := range entries {
+		if entry.EventType() == Removed {
+			// Must be delivered: see the callbackDispatcher doc comment.
+			d.entries <- entry
+			continue
+		}
+
+		select {
+		case d.entries <- entry:
+		default:
+			// Every worker is busy; drop rather than block the caller
+			// that's feeding us, which is ultimately the packet reader.
+		}
+	}
+	close(d.entries)
+	d.wg.Wait()
+}
+
+// BrowseFunc browses for instances of service in domain, invoking fn for
+// every add/update/remove event until ctx is done. It is a callback-oriented
+// alternative to NewLookupParams/Entries for callers who would rather not
+// drain a channel themselves. fn runs on a bounded worker pool; under
+// sustained overload (every worker busy) further Added/Updated/Refreshed
+// events are dropped rather than blocking the packet reader, but Removed
+// is always delivered.
+func BrowseFunc(ctx context.Context, service, domain string, fn EntryCallback) error {
+	entries := make(chan *ServiceEntry)
+	params := NewLookupParams("", service, domain, entries)
+
+	dispatcher := newCallbackDispatcher(fn)
+	go dispatcher.feed(entries)
+
+	if err := browse(ctx, params); err != nil {
+		// browse failed before its mainloop could ever close Entries
+		// (e.g. no usable multicast interface); tear the dispatcher down
+		// ourselves so its workers don't leak. done() is idempotent, so
+		// this is also safe if the mainloop raced us to it.
+		params.done()
+		return err
+	}
+
+	return nil
+}
+
+// ResolveFunc resolves a single service instance, invoking fn whenever the
+// corresponding ServiceEntry changes until ctx is done.
+func ResolveFunc(ctx context.Context, instance, service, domain string, fn EntryCallback) error {
+	entries := make(chan *ServiceEntry)
+	params := NewLookupParams(instance, service, domain, entries)
+
+	dispatcher := newCallbackDispatcher(fn)
+	go dispatcher.feed(entries)
+
+	if err := resolve(ctx, params); err != nil {
+		params.done()
+		return err
+	}
+
+	return nil
+}
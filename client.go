@@ -0,0 +1,422 @@
+package zeroconf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// IPType specifies the IP traffic a client listens for. This does not
+// guarantee that only entries of this specific type pass through, since a
+// typical mDNS packet distributed via IPv4 often carries both A and AAAA
+// records.
+type IPType uint8
+
+// Options for IPType.
+const (
+	IPv4        IPType = 0x01
+	IPv6        IPType = 0x02
+	IPv4AndIPv6        = IPv4 | IPv6 // default
+)
+
+type clientOpts struct {
+	listenOn IPType
+	ifaces   []net.Interface
+}
+
+// ClientOption configures the client constructed by NewBrowser.
+type ClientOption func(*clientOpts)
+
+// SelectIPTraffic selects the type of IP packets (IPv4, IPv6 or both) a
+// client listens for.
+func SelectIPTraffic(t IPType) ClientOption {
+	return func(o *clientOpts) { o.listenOn = t }
+}
+
+// SelectIfaces restricts a client to the given interfaces instead of every
+// up, multicast-capable interface on the host.
+func SelectIfaces(ifaces []net.Interface) ClientOption {
+	return func(o *clientOpts) { o.ifaces = ifaces }
+}
+
+func resolveClientOpts(opts []ClientOption) clientOpts {
+	conf := clientOpts{listenOn: IPv4AndIPv6}
+	for _, o := range opts {
+		if o != nil {
+			o(&conf)
+		}
+	}
+	return conf
+}
+
+// client encapsulates the IPv4/IPv6 multicast sockets shared by a single
+// browse/lookup/NewBrowser call.
+type client struct {
+	ipv4conn *ipv4.PacketConn
+	ipv6conn *ipv6.PacketConn
+	ifaces   []net.Interface
+}
+
+func newClient(opts clientOpts) (*client, error) {
+	ifaces := opts.ifaces
+	if len(ifaces) == 0 {
+		ifaces = listMulticastInterfaces()
+	}
+
+	var ipv4conn *ipv4.PacketConn
+	if opts.listenOn&IPv4 > 0 {
+		var err error
+		if ipv4conn, err = joinUdp4Multicast(ifaces); err != nil {
+			return nil, err
+		}
+	}
+
+	var ipv6conn *ipv6.PacketConn
+	if opts.listenOn&IPv6 > 0 {
+		var err error
+		if ipv6conn, err = joinUdp6Multicast(ifaces); err != nil {
+			return nil, err
+		}
+	}
+
+	if ipv4conn == nil && ipv6conn == nil {
+		return nil, fmt.Errorf("zeroconf: no usable multicast interface")
+	}
+
+	return &client{ipv4conn: ipv4conn, ipv6conn: ipv6conn, ifaces: ifaces}, nil
+}
+
+func (c *client) shutdown() {
+	if c.ipv4conn != nil {
+		c.ipv4conn.Close()
+	}
+	if c.ipv6conn != nil {
+		c.ipv6conn.Close()
+	}
+}
+
+// mainloop listens on both sockets, merges every incoming record into
+// params.cache, forwards the merged entry to params.Entries and, once a
+// confirmed (non-Removed) sighting has been merged, disables probing. It
+// runs regardless of params.Passive: passive mode only suppresses the
+// outbound queries in query/periodicQuery below, not the receive side.
+func (c *client) mainloop(ctx context.Context, params *LookupParams) {
+	msgCh := make(chan *dns.Msg, 32)
+	if c.ipv4conn != nil {
+		go c.recv(ctx, c.ipv4conn, msgCh)
+	}
+	if c.ipv6conn != nil {
+		go c.recv(ctx, c.ipv6conn, msgCh)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			params.done()
+			c.shutdown()
+			return
+		case msg := <-msgCh:
+			for _, entry := range params.parse(msg) {
+				merged := params.cache.merge(entry)
+				if params.Entries != nil {
+					params.Entries <- merged
+				}
+				if merged.eventType != Removed {
+					params.disableProbing()
+				}
+			}
+		}
+	}
+}
+
+func (c *client) recv(ctx context.Context, conn interface{}, msgCh chan *dns.Msg) {
+	var readFrom func([]byte) (int, error)
+	switch pc := conn.(type) {
+	case *ipv4.PacketConn:
+		readFrom = func(b []byte) (int, error) {
+			n, _, _, err := pc.ReadFrom(b)
+			return n, err
+		}
+	case *ipv6.PacketConn:
+		readFrom = func(b []byte) (int, error) {
+			n, _, _, err := pc.ReadFrom(b)
+			return n, err
+		}
+	default:
+		return
+	}
+
+	buf := make([]byte, 65536)
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		n, err := readFrom(buf)
+		if err != nil {
+			continue
+		}
+
+		msg := new(dns.Msg)
+		if err := msg.Unpack(buf[:n]); err != nil {
+			continue
+		}
+
+		select {
+		case msgCh <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// parse groups an incoming dns.Msg's records into the ServiceEntry values
+// relevant to params, first resolving PTR/SRV/TXT (which name each
+// instance) and only then associating A/AAAA records, since those are keyed
+// by the hostname SRV records supply.
+func (params *LookupParams) parse(msg *dns.Msg) []*ServiceEntry {
+	sections := append(append(append([]dns.RR{}, msg.Answer...), msg.Ns...), msg.Extra...)
+	entries := make(map[string]*ServiceEntry)
+
+	for _, answer := range sections {
+		switch rr := answer.(type) {
+		case *dns.PTR:
+			if params.ServiceName() != rr.Hdr.Name {
+				continue
+			}
+			if params.ServiceInstanceName() != "" && params.ServiceInstanceName() != rr.Ptr {
+				continue
+			}
+			if _, ok := entries[rr.Ptr]; !ok {
+				entries[rr.Ptr] = NewServiceEntry(
+					trimDot(strings.Replace(rr.Ptr, rr.Hdr.Name, "", -1)),
+					params.Service, params.Domain)
+			}
+			entries[rr.Ptr].TTL = rr.Hdr.Ttl
+		case *dns.SRV:
+			if params.ServiceInstanceName() != "" && params.ServiceInstanceName() != rr.Hdr.Name {
+				continue
+			} else if !strings.HasSuffix(rr.Hdr.Name, params.ServiceName()) {
+				continue
+			}
+			if _, ok := entries[rr.Hdr.Name]; !ok {
+				entries[rr.Hdr.Name] = NewServiceEntry(
+					trimDot(strings.Replace(rr.Hdr.Name, params.ServiceName(), "", 1)),
+					params.Service, params.Domain)
+			}
+			entries[rr.Hdr.Name].HostName = rr.Target
+			entries[rr.Hdr.Name].Port = int(rr.Port)
+			entries[rr.Hdr.Name].TTL = rr.Hdr.Ttl
+		case *dns.TXT:
+			if params.ServiceInstanceName() != "" && params.ServiceInstanceName() != rr.Hdr.Name {
+				continue
+			} else if !strings.HasSuffix(rr.Hdr.Name, params.ServiceName()) {
+				continue
+			}
+			if _, ok := entries[rr.Hdr.Name]; !ok {
+				entries[rr.Hdr.Name] = NewServiceEntry(
+					trimDot(strings.Replace(rr.Hdr.Name, params.ServiceName(), "", 1)),
+					params.Service, params.Domain)
+			}
+			entries[rr.Hdr.Name].Text = rr.Txt
+			entries[rr.Hdr.Name].TTL = rr.Hdr.Ttl
+		}
+	}
+
+	for _, answer := range sections {
+		switch rr := answer.(type) {
+		case *dns.A:
+			for k, e := range entries {
+				if e.HostName == rr.Hdr.Name {
+					entries[k].AddrIPv4 = append(entries[k].AddrIPv4, rr.A)
+				}
+			}
+		case *dns.AAAA:
+			for k, e := range entries {
+				if e.HostName == rr.Hdr.Name {
+					entries[k].AddrIPv6 = append(entries[k].AddrIPv6, rr.AAAA)
+				}
+			}
+		}
+	}
+
+	result := make([]*ServiceEntry, 0, len(entries))
+	for _, e := range entries {
+		result = append(result, e)
+	}
+	return result
+}
+
+// query sends a single PTR (browse) or SRV+TXT (lookup) question. It is a
+// no-op under Passive, since passive discovery must never transmit.
+func (c *client) query(params *LookupParams) error {
+	if params.Passive {
+		return nil
+	}
+
+	m := new(dns.Msg)
+	if instance := params.ServiceInstanceName(); instance != "" {
+		m.Question = []dns.Question{
+			{Name: instance, Qtype: dns.TypeSRV, Qclass: dns.ClassINET},
+			{Name: instance, Qtype: dns.TypeTXT, Qclass: dns.ClassINET},
+		}
+	} else {
+		m.SetQuestion(params.ServiceName(), dns.TypePTR)
+	}
+	m.RecursionDesired = false
+
+	return c.sendQuery(m)
+}
+
+func (c *client) sendQuery(msg *dns.Msg) error {
+	buf, err := msg.Pack()
+	if err != nil {
+		return err
+	}
+
+	if c.ipv4conn != nil {
+		var wcm ipv4.ControlMessage
+		for _, iface := range c.ifaces {
+			wcm.IfIndex = iface.Index
+			c.ipv4conn.WriteTo(buf, &wcm, ipv4Addr)
+		}
+	}
+	if c.ipv6conn != nil {
+		var wcm ipv6.ControlMessage
+		for _, iface := range c.ifaces {
+			wcm.IfIndex = iface.Index
+			c.ipv6conn.WriteTo(buf, &wcm, ipv6Addr)
+		}
+	}
+
+	return nil
+}
+
+// periodicQuery resends params' query with exponential backoff until a
+// matching entry disables probing (see LookupParams.disableProbing) or ctx
+// is done. The wait starts at periodicQueryInitialInterval and doubles
+// after each query until it is at least periodicQueryMaxInterval, after
+// which it stays fixed. Passive requests never reach the send at all.
+const (
+	periodicQueryInitialInterval = 4 * time.Second
+	periodicQueryMaxInterval     = 60 * time.Second
+)
+
+func (c *client) periodicQuery(ctx context.Context, params *LookupParams) error {
+	if params.Passive {
+		return nil
+	}
+
+	wait := periodicQueryInitialInterval
+	for {
+		if err := c.query(params); err != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(wait):
+			if wait < periodicQueryMaxInterval {
+				wait *= 2
+			}
+		case <-params.stopProbing:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// browse starts listening for responses to params and, unless params is
+// Passive, issues the initial query plus a backing-off periodicQuery. It
+// returns once the initial query has been sent (or immediately, for
+// passive requests); the mainloop and any periodicQuery continue in the
+// background until ctx is done.
+func browse(ctx context.Context, params *LookupParams) error {
+	c, err := newClient(clientOpts{listenOn: IPv4AndIPv6})
+	if err != nil {
+		return err
+	}
+
+	if params.Passive {
+		go c.mainloop(ctx, params)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go c.mainloop(ctx, params)
+
+	if err := c.query(params); err != nil {
+		cancel()
+		return err
+	}
+
+	go func() {
+		if err := c.periodicQuery(ctx, params); err != nil {
+			cancel()
+		}
+	}()
+
+	return nil
+}
+
+// resolve is browse's counterpart for a single service instance; the
+// distinction lives entirely in whether params.Instance is set.
+func resolve(ctx context.Context, params *LookupParams) error {
+	return browse(ctx, params)
+}
+
+// Browse browses for instances of service in domain, writing results to
+// entries until ctx is done.
+func Browse(ctx context.Context, service, domain string, entries chan<- *ServiceEntry) error {
+	return browse(ctx, NewLookupParams("", service, domain, entries))
+}
+
+// Lookup resolves a single service instance, writing results to entries
+// until ctx is done.
+func Lookup(ctx context.Context, instance, service, domain string, entries chan<- *ServiceEntry) error {
+	return browse(ctx, NewLookupParams(instance, service, domain, entries))
+}
+
+// LookupName browses using an already-composed DNS-SD name — such as the
+// "_services._dns-sd._udp.<domain>." meta-query name returned by
+// ServiceRecord.ServiceTypeName() — instead of composing one from a
+// separate service/domain pair.
+func LookupName(ctx context.Context, name string, entries chan<- *ServiceEntry) error {
+	params := NewLookupParams("", "", "", entries)
+	params.ServiceRecord.serviceName = name
+	return browse(ctx, params)
+}
+
+// NewBrowser starts browsing for instances of service in domain and
+// returns a Browser backed by the live cache that browsing feeds, so
+// Entries()/Subscribe() reflect it as results arrive.
+func NewBrowser(ctx context.Context, service, domain string, opts ...ClientOption) (*Browser, error) {
+	c, err := newClient(resolveClientOpts(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	params := NewLookupParams("", service, domain, nil)
+	b := &Browser{cache: params.cache}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go c.mainloop(ctx, params)
+
+	if err := c.query(params); err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		if err := c.periodicQuery(ctx, params); err != nil {
+			cancel()
+		}
+	}()
+
+	return b, nil
+}
@@ -0,0 +1,137 @@
+package zeroconf
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// TestPassiveLookupSendsNoQueries verifies that a passive LookupParams
+// (see NewPassiveLookupParams) never transmits a query; that an equivalent
+// active lookup does is checked by TestActiveLookupSendsQuery. It listens
+// on the mDNS multicast group
+// itself, independent of this package's own sockets, so it observes
+// exactly what went out on the wire.
+func TestPassiveLookupSendsNoQueries(t *testing.T) {
+	const service = "_passivetest._tcp"
+
+	sniffConn, err := net.ListenMulticastUDP("udp4", nil, &net.UDPAddr{IP: mdnsGroupIPv4, Port: 5353})
+	if err != nil {
+		t.Skipf("multicast not available in this environment: %v", err)
+	}
+	defer sniffConn.Close()
+	sniffConn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries := make(chan *ServiceEntry, 4)
+	params := NewPassiveLookupParams(service, "local", entries)
+	if !params.Passive {
+		t.Fatal("NewPassiveLookupParams did not set Passive")
+	}
+	if err := browse(ctx, params); err != nil {
+		t.Skipf("browse unavailable in this environment: %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := sniffConn.ReadFromUDP(buf)
+		if err != nil {
+			break // deadline hit: nothing arrived, as expected
+		}
+
+		msg := new(dns.Msg)
+		if err := msg.Unpack(buf[:n]); err != nil {
+			continue
+		}
+		for _, q := range msg.Question {
+			if q.Name == params.ServiceName() {
+				t.Fatalf("passive lookup sent a query for %s, want none", q.Name)
+			}
+		}
+	}
+}
+
+// TestPassiveLookupPopulatesEntriesFromAnnouncement verifies the other half
+// of passive discovery's contract: it never queries (see
+// TestPassiveLookupSendsNoQueries above), but it must still learn about
+// instances from the unsolicited announcements other responders broadcast
+// on their own schedule (RFC 6762 §8.3). It registers a real service via
+// Register, whose announce() sends exactly such an unsolicited packet, and
+// checks a concurrent passive lookup observes it on Entries.
+func TestPassiveLookupPopulatesEntriesFromAnnouncement(t *testing.T) {
+	const service = "_passiveannounce._tcp"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries := make(chan *ServiceEntry, 8)
+	params := NewPassiveLookupParams(service, "local", entries)
+	if err := browse(ctx, params); err != nil {
+		t.Skipf("browse unavailable in this environment: %v", err)
+	}
+
+	srv, err := Register("passive-instance", service, "local", 8123, []string{"txtvers=1"}, nil)
+	if err != nil {
+		t.Skipf("register unavailable in this environment: %v", err)
+	}
+	defer srv.Shutdown()
+
+	select {
+	case e := <-entries:
+		if e.Instance != "passive-instance" {
+			t.Errorf("got instance %q, want passive-instance", e.Instance)
+		}
+		if e.Port != 8123 {
+			t.Errorf("got port %d, want 8123", e.Port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("passive lookup never observed the unsolicited announcement")
+	}
+}
+
+// TestActiveLookupSendsQuery is TestPassiveLookupSendsNoQueries's
+// counterpart: a non-passive lookup for the same service must transmit at
+// least one query, so the passive test above is actually exercising
+// Passive and not just an environment where nothing ever queries.
+func TestActiveLookupSendsQuery(t *testing.T) {
+	const service = "_activetest._tcp"
+
+	sniffConn, err := net.ListenMulticastUDP("udp4", nil, &net.UDPAddr{IP: mdnsGroupIPv4, Port: 5353})
+	if err != nil {
+		t.Skipf("multicast not available in this environment: %v", err)
+	}
+	defer sniffConn.Close()
+	sniffConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	entries := make(chan *ServiceEntry, 4)
+	params := NewLookupParams("", service, "local", entries)
+	if err := browse(ctx, params); err != nil {
+		t.Skipf("browse unavailable in this environment: %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := sniffConn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("active lookup for %s sent no query before deadline", params.ServiceName())
+		}
+
+		msg := new(dns.Msg)
+		if err := msg.Unpack(buf[:n]); err != nil {
+			continue
+		}
+		for _, q := range msg.Question {
+			if q.Name == params.ServiceName() {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package zeroconf
+
+import (
+	"fmt"
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+var (
+	// mDNS multicast groups.
+	mdnsGroupIPv4 = net.IPv4(224, 0, 0, 251)
+	mdnsGroupIPv6 = net.ParseIP("ff02::fb")
+
+	// mDNS wildcard bind addresses.
+	mdnsWildcardAddrIPv4 = &net.UDPAddr{IP: net.ParseIP("224.0.0.0"), Port: 5353}
+	mdnsWildcardAddrIPv6 = &net.UDPAddr{IP: net.ParseIP("ff02::"), Port: 5353}
+
+	// mDNS destination addresses.
+	ipv4Addr = &net.UDPAddr{IP: mdnsGroupIPv4, Port: 5353}
+	ipv6Addr = &net.UDPAddr{IP: mdnsGroupIPv6, Port: 5353}
+)
+
+func joinUdp4Multicast(ifaces []net.Interface) (*ipv4.PacketConn, error) {
+	conn, err := net.ListenUDP("udp4", mdnsWildcardAddrIPv4)
+	if err != nil {
+		return nil, err
+	}
+
+	pkConn := ipv4.NewPacketConn(conn)
+	pkConn.SetControlMessage(ipv4.FlagInterface, true)
+
+	if len(ifaces) == 0 {
+		ifaces = listMulticastInterfaces()
+	}
+
+	var failed int
+	for _, iface := range ifaces {
+		if err := pkConn.JoinGroup(&iface, &net.UDPAddr{IP: mdnsGroupIPv4}); err != nil {
+			failed++
+		}
+	}
+	if failed == len(ifaces) {
+		pkConn.Close()
+		return nil, fmt.Errorf("zeroconf: udp4: failed to join any of these interfaces: %v", ifaces)
+	}
+
+	return pkConn, nil
+}
+
+func joinUdp6Multicast(ifaces []net.Interface) (*ipv6.PacketConn, error) {
+	conn, err := net.ListenUDP("udp6", mdnsWildcardAddrIPv6)
+	if err != nil {
+		return nil, err
+	}
+
+	pkConn := ipv6.NewPacketConn(conn)
+	pkConn.SetControlMessage(ipv6.FlagInterface, true)
+
+	if len(ifaces) == 0 {
+		ifaces = listMulticastInterfaces()
+	}
+
+	var failed int
+	for _, iface := range ifaces {
+		if err := pkConn.JoinGroup(&iface, &net.UDPAddr{IP: mdnsGroupIPv6}); err != nil {
+			failed++
+		}
+	}
+	if failed == len(ifaces) {
+		pkConn.Close()
+		return nil, fmt.Errorf("zeroconf: udp6: failed to join any of these interfaces: %v", ifaces)
+	}
+
+	return pkConn, nil
+}
+
+// listMulticastInterfaces returns every up interface capable of multicast.
+func listMulticastInterfaces() []net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var out []net.Interface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		out = append(out, iface)
+	}
+
+	return out
+}
+
+// addrsForInterface splits iface's non-loopback addresses into IPv4/IPv6,
+// preferring global-unicast IPv6 over link-local when both are present.
+func addrsForInterface(iface *net.Interface) ([]net.IP, []net.IP) {
+	var v4, v6, v6local []net.IP
+
+	addrs, _ := iface.Addrs()
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() {
+			continue
+		}
+		if ipnet.IP.To4() != nil {
+			v4 = append(v4, ipnet.IP)
+			continue
+		}
+		if ip := ipnet.IP.To16(); ip != nil {
+			if ip.IsGlobalUnicast() {
+				v6 = append(v6, ipnet.IP)
+			} else if ip.IsLinkLocalUnicast() {
+				v6local = append(v6local, ipnet.IP)
+			}
+		}
+	}
+	if len(v6) == 0 {
+		v6 = v6local
+	}
+
+	return v4, v6
+}
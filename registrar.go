@@ -0,0 +1,168 @@
+package zeroconf
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// RecordRegistrar publishes arbitrary DNS records — PTR, SRV, TXT, A/AAAA,
+// NSEC negative responses, and subtype PTRs such as
+// "_printer._sub._http._tcp.local." — without going through the
+// single-instance/service/port ServiceEntry model. It participates in the
+// same conflict-detection/probing state machine as Server, and answers
+// queries for any owner name in its record set. Register and RegisterProxy
+// do not go through RecordRegistrar; they use the same underlying responder
+// directly.
+type RecordRegistrar struct {
+	server *server
+
+	mu      sync.RWMutex
+	records map[string][]dns.RR // keyed by owner name
+}
+
+// NewRecordRegistrar probes for conflicts on the owner names of records and,
+// once clear, announces and publishes them. Each record's cache-flush bit is
+// honored as given; callers republishing a changed value for an
+// already-advertised name should set it so peers purge their caches
+// immediately instead of waiting out the TTL.
+func NewRecordRegistrar(records ...dns.RR) (*RecordRegistrar, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("zeroconf: NewRecordRegistrar requires at least one record")
+	}
+
+	r := &RecordRegistrar{records: make(map[string][]dns.RR)}
+	for _, rr := range records {
+		name := rr.Header().Name
+		r.records[name] = append(r.records[name], rr)
+	}
+
+	srv, err := newServer(r.answer)
+	if err != nil {
+		return nil, fmt.Errorf("zeroconf: start registrar: %w", err)
+	}
+	r.server = srv
+
+	if err := srv.probe(r.ownerNames()); err != nil {
+		srv.shutdown()
+		return nil, fmt.Errorf("zeroconf: probe registrar records: %w", err)
+	}
+	srv.announce()
+
+	return r, nil
+}
+
+// Add probes for conflicts on rr's owner name, then publishes it alongside
+// whatever is already registered under that name. A name this registrar
+// already publishes is not probed again.
+func (r *RecordRegistrar) Add(rr dns.RR) error {
+	name := rr.Header().Name
+
+	r.mu.RLock()
+	_, owned := r.records[name]
+	r.mu.RUnlock()
+
+	// Probing a name we already answer for would draw our own reply and
+	// be reported as a conflict.
+	if !owned {
+		if err := r.server.probe([]string{name}); err != nil {
+			return fmt.Errorf("zeroconf: probe %s: %w", name, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.records[name] = append(r.records[name], rr)
+	r.mu.Unlock()
+
+	r.server.announce()
+	return nil
+}
+
+// Records returns a snapshot of everything currently published, keyed by
+// owner name.
+func (r *RecordRegistrar) Records() map[string][]dns.RR {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string][]dns.RR, len(r.records))
+	for name, rrs := range r.records {
+		out[name] = append([]dns.RR(nil), rrs...)
+	}
+	return out
+}
+
+func (r *RecordRegistrar) ownerNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.records))
+	for name := range r.records {
+		names = append(names, name)
+	}
+	return names
+}
+
+// answer is the server's query callback: every record owned by the queried
+// name whose type matches q.Qtype (or all of them, for TypeANY) is
+// returned verbatim, cache-flush bit and all — the same filtering
+// answerFor applies for a single registered ServiceEntry.
+func (r *RecordRegistrar) answer(q dns.Question) []dns.RR {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []dns.RR
+	for _, rr := range r.records[q.Name] {
+		if q.Qtype == dns.TypeANY || rr.Header().Rrtype == q.Qtype {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+// Close sends the mandatory goodbye packets (TTL=0) for every published
+// record and shuts down the underlying responder.
+func (r *RecordRegistrar) Close() error {
+	r.mu.RLock()
+	var goodbyes []dns.RR
+	for _, rrs := range r.records {
+		for _, rr := range rrs {
+			gb := dns.Copy(rr)
+			gb.Header().Ttl = 0
+			goodbyes = append(goodbyes, gb)
+		}
+	}
+	r.mu.RUnlock()
+
+	r.server.sendGoodbye(goodbyes)
+	return r.server.shutdown()
+}
+
+// NewSubtypePTR builds a DNS-SD subtype PTR record, e.g. advertising
+// "_printer" as a subtype of "_http._tcp" yields an owner name of
+// "_printer._sub._http._tcp.local." pointing at "_http._tcp.local.".
+func NewSubtypePTR(subtype, parent, domain string, ttl uint32) dns.RR {
+	owner := fmt.Sprintf("%s._sub.%s.%s.", trimDot(subtype), trimDot(parent), trimDot(domain))
+	target := fmt.Sprintf("%s.%s.", trimDot(parent), trimDot(domain))
+
+	return &dns.PTR{
+		Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: ttl},
+		Ptr: target,
+	}
+}
+
+// NewBrowsingDomainPTR builds a "b._dns-sd._udp.<domain>." record, or its
+// "lb." legacy-browsing-domain counterpart, advertising domain per RFC 6763
+// section 11.
+func NewBrowsingDomainPTR(legacy bool, domain string, ttl uint32) dns.RR {
+	label := "b"
+	if legacy {
+		label = "lb"
+	}
+	owner := fmt.Sprintf("%s._dns-sd._udp.%s.", label, trimDot(domain))
+
+	return &dns.PTR{
+		Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: ttl},
+		Ptr: trimDot(domain) + ".",
+	}
+}
@@ -0,0 +1,217 @@
+// Package registry implements github.com/micro/go-micro/registry.Registry on
+// top of zeroconf's Server/Browser, so go-micro services can use mDNS as
+// their discovery backend instead of the abandoned util/mdns fork.
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	mregistry "github.com/micro/go-micro/registry"
+
+	"github.com/deadbeefcafe/zeroconf"
+)
+
+// domain is the mDNS domain this registry operates in; go-micro has no
+// concept of a DNS-SD domain, so it is fixed rather than configurable.
+const domain = "local"
+
+// lookupTimeout bounds how long GetService/ListServices wait for replies to
+// their one-shot mDNS query before returning what they have.
+const lookupTimeout = 600 * time.Millisecond
+
+// mdnsRegistry adapts zeroconf to the go-micro registry.Registry interface.
+type mdnsRegistry struct {
+	opts mregistry.Options
+
+	mu      sync.Mutex
+	servers map[string]*zeroconf.Server // keyed by "<service>/<node id>"
+}
+
+// NewRegistry constructs a go-micro registry.Registry backed by mDNS.
+func NewRegistry(opts ...mregistry.Option) mregistry.Registry {
+	var options mregistry.Options
+	for _, o := range opts {
+		o(&options)
+	}
+
+	return &mdnsRegistry{
+		opts:    options,
+		servers: make(map[string]*zeroconf.Server),
+	}
+}
+
+func (r *mdnsRegistry) Init(opts ...mregistry.Option) error {
+	for _, o := range opts {
+		o(&r.opts)
+	}
+	return nil
+}
+
+func (r *mdnsRegistry) Options() mregistry.Options {
+	return r.opts
+}
+
+func (r *mdnsRegistry) String() string {
+	return "zeroconf"
+}
+
+// serviceType maps a go-micro service name to the mDNS service type it is
+// advertised and browsed under.
+func serviceType(name string) string {
+	return "_" + name + "._tcp"
+}
+
+func (r *mdnsRegistry) Register(s *mregistry.Service, opts ...mregistry.RegisterOption) error {
+	if len(s.Nodes) == 0 {
+		return fmt.Errorf("registry: %s has no nodes to register", s.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, node := range s.Nodes {
+		key := s.Name + "/" + node.Id
+		if _, ok := r.servers[key]; ok {
+			continue
+		}
+
+		text, err := encodeMetadata(s, node)
+		if err != nil {
+			return err
+		}
+
+		server, err := zeroconf.Register(node.Id, serviceType(s.Name), domain, node.Port, text, nil)
+		if err != nil {
+			return fmt.Errorf("registry: register %s: %w", key, err)
+		}
+		r.servers[key] = server
+	}
+
+	return nil
+}
+
+func (r *mdnsRegistry) Deregister(s *mregistry.Service) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, node := range s.Nodes {
+		key := s.Name + "/" + node.Id
+		server, ok := r.servers[key]
+		if !ok {
+			continue
+		}
+		server.Shutdown()
+		delete(r.servers, key)
+	}
+
+	return nil
+}
+
+func (r *mdnsRegistry) GetService(name string) ([]*mregistry.Service, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	entries := make(chan *zeroconf.ServiceEntry, 8)
+	if err := zeroconf.Browse(ctx, serviceType(name), domain, entries); err != nil {
+		return nil, fmt.Errorf("registry: get service %s: %w", name, err)
+	}
+
+	// go-micro returns one *Service per version, so differing-version nodes
+	// must not collapse into a single Service carrying only the last one
+	// seen; group by version first and build a Service per group.
+	byVersion := make(map[string]*mregistry.Service)
+	var order []string
+	for e := range entries {
+		version, meta, err := decodeMetadata(e.Text)
+		if err != nil {
+			continue
+		}
+
+		svc, ok := byVersion[version]
+		if !ok {
+			svc = &mregistry.Service{Name: name, Version: version, Metadata: meta.Metadata, Endpoints: meta.Endpoints}
+			byVersion[version] = svc
+			order = append(order, version)
+		}
+		svc.Nodes = append(svc.Nodes, entryToNode(e, meta))
+	}
+
+	if len(byVersion) == 0 {
+		return nil, errors.New("registry: service not found: " + name)
+	}
+
+	services := make([]*mregistry.Service, len(order))
+	for i, version := range order {
+		services[i] = byVersion[version]
+	}
+
+	return services, nil
+}
+
+// ListServices enumerates every service type currently advertised on the
+// network via the DNS-SD "_services._dns-sd._udp.<domain>." meta-query.
+func (r *mdnsRegistry) ListServices() ([]*mregistry.Service, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	rec := zeroconf.NewServiceRecord("", "_services._dns-sd._udp", domain)
+	entries := make(chan *zeroconf.ServiceEntry, 8)
+
+	if err := zeroconf.LookupName(ctx, rec.ServiceTypeName(), entries); err != nil {
+		return nil, fmt.Errorf("registry: list services: %w", err)
+	}
+
+	var services []*mregistry.Service
+	for e := range entries {
+		services = append(services, &mregistry.Service{Name: e.Instance})
+	}
+
+	return services, nil
+}
+
+func (r *mdnsRegistry) Watch(opts ...mregistry.WatchOption) (mregistry.Watcher, error) {
+	var options mregistry.WatchOptions
+	for _, o := range opts {
+		o(&options)
+	}
+	if options.Service == "" {
+		return nil, errors.New("registry: Watch requires a service name")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	browser, err := zeroconf.NewBrowser(ctx, serviceType(options.Service), domain)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("registry: watch %s: %w", options.Service, err)
+	}
+
+	raw := serviceType(options.Service)
+	return &watcher{
+		service: options.Service,
+		browser: browser,
+		raw:     raw,
+		sub:     browser.Subscribe(raw),
+		cancel:  cancel,
+	}, nil
+}
+
+func entryToNode(e *zeroconf.ServiceEntry, meta serviceMeta) *mregistry.Node {
+	addr := meta.Address
+	switch {
+	case len(e.AddrIPv4) > 0:
+		addr = e.AddrIPv4[0].String()
+	case len(e.AddrIPv6) > 0:
+		addr = e.AddrIPv6[0].String()
+	}
+
+	return &mregistry.Node{
+		Id:       e.Instance,
+		Address:  addr,
+		Port:     e.Port,
+		Metadata: meta.NodeMeta,
+	}
+}
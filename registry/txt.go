@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	mregistry "github.com/micro/go-micro/registry"
+)
+
+// maxTXTStringLen is the DNS-SD limit on a single TXT character-string.
+const maxTXTStringLen = 255
+
+// maxTXTTotalLen is the practical ceiling most mDNS stacks apply to a TXT
+// record's combined size; encodeMetadata refuses to exceed it rather than
+// producing a record a responder would truncate or drop.
+const maxTXTTotalLen = 1300
+
+// chunkPrefixBudget reserves room in each 255-byte TXT string for its "eNN="
+// key, comfortably covering chunk indices into the thousands.
+const chunkPrefixBudget = 8
+
+// serviceMeta is the JSON payload chunked across the e0=, e1=, ... TXT
+// strings. It carries everything about the go-micro Service/Node pair that
+// doesn't fit in the ServiceEntry's own Port/HostName/AddrIPv4/AddrIPv6.
+type serviceMeta struct {
+	Address   string                `json:"address,omitempty"`
+	Metadata  map[string]string     `json:"metadata,omitempty"`
+	NodeMeta  map[string]string     `json:"node_metadata,omitempty"`
+	Endpoints []*mregistry.Endpoint `json:"endpoints,omitempty"`
+}
+
+// encodeMetadata packs a go-micro Service/Node pair into the TXT strings
+// registered alongside the mDNS service instance: "v=<version>" followed by
+// base64 chunks of the JSON-encoded serviceMeta under continuation keys
+// e0=, e1=, ... A single TXT string caps out at 255 bytes, so anything
+// larger than that must be split to round-trip.
+func encodeMetadata(s *mregistry.Service, node *mregistry.Node) ([]string, error) {
+	raw, err := json.Marshal(serviceMeta{
+		Address:   node.Address,
+		Metadata:  s.Metadata,
+		NodeMeta:  node.Metadata,
+		Endpoints: s.Endpoints,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("registry: encode metadata for %s: %w", s.Name, err)
+	}
+
+	text := []string{"v=" + s.Version}
+	total := len(text[0])
+
+	for i, chunk := range chunkString(base64.RawURLEncoding.EncodeToString(raw)) {
+		kv := "e" + strconv.Itoa(i) + "=" + chunk
+		total += len(kv)
+		if total > maxTXTTotalLen {
+			return nil, fmt.Errorf("registry: metadata for %s exceeds %d-byte TXT budget", s.Name, maxTXTTotalLen)
+		}
+		text = append(text, kv)
+	}
+
+	return text, nil
+}
+
+// decodeMetadata reverses encodeMetadata, reassembling the e0=, e1=, ...
+// continuation keys in order before base64/JSON decoding. Keys are read
+// until a gap is found, so a missing chunk truncates rather than panics.
+func decodeMetadata(text []string) (string, serviceMeta, error) {
+	var version string
+	chunks := make(map[int]string, len(text))
+
+	for _, kv := range text {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		switch {
+		case k == "v":
+			version = v
+		case strings.HasPrefix(k, "e"):
+			if n, err := strconv.Atoi(k[1:]); err == nil {
+				chunks[n] = v
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; ; i++ {
+		chunk, ok := chunks[i]
+		if !ok {
+			break
+		}
+		b.WriteString(chunk)
+	}
+
+	if b.Len() == 0 {
+		return version, serviceMeta{}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(b.String())
+	if err != nil {
+		return version, serviceMeta{}, fmt.Errorf("registry: decode metadata: %w", err)
+	}
+
+	var meta serviceMeta
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return version, serviceMeta{}, fmt.Errorf("registry: unmarshal metadata: %w", err)
+	}
+
+	return version, meta, nil
+}
+
+// chunkString splits s into pieces that fit within a single TXT
+// character-string once its "eNN=" key is accounted for.
+func chunkString(s string) []string {
+	size := maxTXTStringLen - chunkPrefixBudget
+
+	var chunks []string
+	for len(s) > 0 {
+		n := size
+		if n > len(s) {
+			n = len(s)
+		}
+		chunks = append(chunks, s[:n])
+		s = s[n:]
+	}
+
+	return chunks
+}
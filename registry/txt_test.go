@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	mregistry "github.com/micro/go-micro/registry"
+)
+
+func TestEncodeDecodeMetadataRoundTrip(t *testing.T) {
+	s := &mregistry.Service{
+		Name:     "greeter",
+		Version:  "1.2.3",
+		Metadata: map[string]string{"region": "us-east"},
+		Endpoints: []*mregistry.Endpoint{
+			{Name: "Greeter.Hello"},
+		},
+	}
+	node := &mregistry.Node{
+		Id:       "greeter-1",
+		Address:  "10.0.0.5:8080",
+		Metadata: map[string]string{"zone": "a"},
+	}
+
+	text, err := encodeMetadata(s, node)
+	if err != nil {
+		t.Fatalf("encodeMetadata: %v", err)
+	}
+
+	version, meta, err := decodeMetadata(text)
+	if err != nil {
+		t.Fatalf("decodeMetadata: %v", err)
+	}
+
+	if version != s.Version {
+		t.Errorf("version = %q, want %q", version, s.Version)
+	}
+	if meta.Address != node.Address {
+		t.Errorf("address = %q, want %q", meta.Address, node.Address)
+	}
+	if meta.Metadata["region"] != "us-east" {
+		t.Errorf("metadata[region] = %q, want us-east", meta.Metadata["region"])
+	}
+	if meta.NodeMeta["zone"] != "a" {
+		t.Errorf("node_metadata[zone] = %q, want a", meta.NodeMeta["zone"])
+	}
+	if len(meta.Endpoints) != 1 || meta.Endpoints[0].Name != "Greeter.Hello" {
+		t.Errorf("endpoints = %+v, want one Greeter.Hello endpoint", meta.Endpoints)
+	}
+}
+
+func TestEncodeMetadataChunksLargePayload(t *testing.T) {
+	s := &mregistry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Metadata: map[string]string{
+			"description": strings.Repeat("x", 600), // forces >255-byte base64 payload
+		},
+	}
+	node := &mregistry.Node{Id: "greeter-1", Address: "10.0.0.5:8080"}
+
+	text, err := encodeMetadata(s, node)
+	if err != nil {
+		t.Fatalf("encodeMetadata: %v", err)
+	}
+
+	for _, kv := range text {
+		if len(kv) > maxTXTStringLen {
+			t.Fatalf("TXT string %q exceeds %d bytes", kv, maxTXTStringLen)
+		}
+	}
+
+	var chunkKeys int
+	for _, kv := range text {
+		if strings.HasPrefix(kv, "e") {
+			chunkKeys++
+		}
+	}
+	if chunkKeys < 2 {
+		t.Fatalf("got %d chunk keys for a >255-byte payload, want at least 2", chunkKeys)
+	}
+
+	version, meta, err := decodeMetadata(text)
+	if err != nil {
+		t.Fatalf("decodeMetadata: %v", err)
+	}
+	if version != s.Version {
+		t.Errorf("version = %q, want %q", version, s.Version)
+	}
+	if meta.Metadata["description"] != s.Metadata["description"] {
+		t.Errorf("description round-trip mismatch")
+	}
+}
+
+func TestEncodeMetadataOverBudget(t *testing.T) {
+	s := &mregistry.Service{
+		Name:    "greeter",
+		Version: "1.0.0",
+		Metadata: map[string]string{
+			"description": strings.Repeat("x", 4*maxTXTTotalLen),
+		},
+	}
+	node := &mregistry.Node{Id: "greeter-1"}
+
+	if _, err := encodeMetadata(s, node); err == nil {
+		t.Fatal("encodeMetadata: want error for a payload over the TXT budget, got nil")
+	}
+}
+
+func TestDecodeMetadataEmpty(t *testing.T) {
+	version, meta, err := decodeMetadata(nil)
+	if err != nil {
+		t.Fatalf("decodeMetadata(nil): %v", err)
+	}
+	if version != "" {
+		t.Errorf("version = %q, want empty", version)
+	}
+	if meta.Address != "" || meta.Metadata != nil {
+		t.Errorf("meta = %+v, want zero value", meta)
+	}
+}
@@ -0,0 +1,63 @@
+package registry
+
+import (
+	"context"
+
+	mregistry "github.com/micro/go-micro/registry"
+
+	"github.com/deadbeefcafe/zeroconf"
+)
+
+// watcher adapts a zeroconf.Browser subscription to go-micro's pull-style
+// Watcher interface.
+type watcher struct {
+	service string
+	browser *zeroconf.Browser
+	raw     string // service name passed to browser.Subscribe/Unsubscribe
+	sub     <-chan zeroconf.ServiceEntry
+	cancel  context.CancelFunc
+}
+
+func (w *watcher) Next() (*mregistry.Result, error) {
+	for {
+		e, ok := <-w.sub
+		if !ok {
+			return nil, mregistry.ErrWatcherStopped
+		}
+
+		// Refreshed is a TTL-only re-announce with no actual change; surfacing
+		// it as an "update" would flood watchers on every periodic announcement,
+		// exactly the churn chunk0-3 added this event to suppress.
+		if e.EventType() == zeroconf.Refreshed {
+			continue
+		}
+
+		version, meta, err := decodeMetadata(e.Text)
+		if err != nil {
+			return nil, err
+		}
+
+		action := "update"
+		switch e.EventType() {
+		case zeroconf.Added:
+			action = "create"
+		case zeroconf.Removed:
+			action = "delete"
+		}
+
+		return &mregistry.Result{
+			Action: action,
+			Service: &mregistry.Service{
+				Name:     w.service,
+				Version:  version,
+				Metadata: meta.Metadata,
+				Nodes:    []*mregistry.Node{entryToNode(&e, meta)},
+			},
+		}, nil
+	}
+}
+
+func (w *watcher) Stop() {
+	w.browser.Unsubscribe(w.raw, w.sub)
+	w.cancel()
+}
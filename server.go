@@ -0,0 +1,405 @@
+package zeroconf
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// probeCount/probeInterval follow RFC 6762 §8.1: three probe queries spaced
+// 250ms apart before a name may be announced.
+const (
+	probeCount       = 3
+	probeInterval    = 250 * time.Millisecond
+	defaultTTL       = 120
+	qClassCacheFlush = 1 << 15
+)
+
+// server is the shared responder/prober machinery behind both Server
+// (single ServiceEntry) and RecordRegistrar (arbitrary records): it owns
+// the multicast sockets, answers incoming questions via answer, and drives
+// the probe/announce/goodbye state machine RFC 6762 requires of anything
+// publishing a name on the local link.
+type server struct {
+	c      *client
+	answer func(dns.Question) []dns.RR
+
+	mu             sync.Mutex
+	names          []string      // owner names probed or published so far, deduped; what announce() re-sends
+	probeResponses chan *dns.Msg // non-nil only while a probe() call is in flight
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newServer starts listening for queries and answers them with answer,
+// which is consulted once per question in an incoming packet and may
+// return nil if it owns nothing matching that question.
+func newServer(answer func(dns.Question) []dns.RR) (*server, error) {
+	c, err := newClient(clientOpts{listenOn: IPv4AndIPv6})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &server{c: c, answer: answer, ctx: ctx, cancel: cancel}
+
+	msgCh := make(chan *dns.Msg, 32)
+	if c.ipv4conn != nil {
+		go c.recv(ctx, c.ipv4conn, msgCh)
+	}
+	if c.ipv6conn != nil {
+		go c.recv(ctx, c.ipv6conn, msgCh)
+	}
+	go s.respond(msgCh)
+
+	return s, nil
+}
+
+func (s *server) respond(msgCh chan *dns.Msg) {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case msg := <-msgCh:
+			if msg.Response {
+				s.handleProbeResponse(msg)
+				continue
+			}
+			s.handleQuery(msg)
+		}
+	}
+}
+
+// handleProbeResponse forwards msg to an in-flight probe() call, if any, so
+// it can check whether msg answers for one of the names being probed.
+// Responses are otherwise irrelevant to this responder and are dropped.
+func (s *server) handleProbeResponse(msg *dns.Msg) {
+	s.mu.Lock()
+	ch := s.probeResponses
+	s.mu.Unlock()
+	if ch == nil {
+		return
+	}
+
+	select {
+	case ch <- msg:
+	default:
+	}
+}
+
+// handleQuery answers every question in msg whose name has completed
+// probing or was published (is in s.names) and that s.answer has records
+// for, sending one
+// multicast response per query packet. Per RFC 6762 §8.1, a name must not
+// be answered for until its probe has completed; this also keeps a probe
+// query that loops back to our own socket from being mistaken for another
+// responder's reply.
+func (s *server) handleQuery(msg *dns.Msg) {
+	s.mu.Lock()
+	published := make(map[string]bool, len(s.names))
+	for _, n := range s.names {
+		published[n] = true
+	}
+	s.mu.Unlock()
+
+	var rrs []dns.RR
+	for _, q := range msg.Question {
+		if !published[q.Name] {
+			continue
+		}
+		rrs = append(rrs, s.answer(q)...)
+	}
+	if len(rrs) == 0 {
+		return
+	}
+
+	resp := new(dns.Msg)
+	resp.Response = true
+	resp.Authoritative = true
+	resp.Answer = rrs
+	s.c.sendQuery(resp)
+}
+
+// probe sends probeCount probe queries for names, spaced probeInterval
+// apart, and listens for a conflicting response in between each one (RFC
+// 6762 §8.1): any answer bearing one of names means another responder
+// already owns it, so probe aborts instead of letting the caller announce
+// over it. names are remembered for subsequent handleQuery/announce() use
+// only once probing completes without a conflict.
+func (s *server) probe(names []string) error {
+	respCh := make(chan *dns.Msg, 16)
+	s.mu.Lock()
+	s.probeResponses = respCh
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.probeResponses = nil
+		s.mu.Unlock()
+	}()
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	m := new(dns.Msg)
+	for _, name := range names {
+		m.Question = append(m.Question, dns.Question{Name: name, Qtype: dns.TypeANY, Qclass: dns.ClassINET})
+	}
+
+	for i := 0; i < probeCount; i++ {
+		if err := s.c.sendQuery(m); err != nil {
+			return err
+		}
+
+		deadline := time.After(probeInterval)
+	waitForConflict:
+		for {
+			select {
+			case resp := <-respCh:
+				for _, rr := range resp.Answer {
+					if wanted[rr.Header().Name] {
+						return fmt.Errorf("zeroconf: probe conflict: %s is already in use on the network", rr.Header().Name)
+					}
+				}
+			case <-deadline:
+				break waitForConflict
+			}
+		}
+	}
+
+	s.publish(names)
+
+	return nil
+}
+
+// publish adds names to the set handleQuery/announce treat as ready to
+// answer for, without probing them for a conflict first. Use this for
+// legitimately shared owner names (e.g. a service type's PTR, which every
+// instance of that type answers for) that probe's single-owner conflict
+// check would otherwise reject.
+func (s *server) publish(names []string) {
+	s.mu.Lock()
+	for _, name := range names {
+		if !containsString(s.names, name) {
+			s.names = append(s.names, name)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// announce sends an unsolicited multicast response for every name probe
+// or publish has accumulated so far, per RFC 6762 §8.3.
+func (s *server) announce() {
+	s.mu.Lock()
+	names := append([]string(nil), s.names...)
+	s.mu.Unlock()
+
+	var rrs []dns.RR
+	for _, name := range names {
+		rrs = append(rrs, s.answer(dns.Question{Name: name, Qtype: dns.TypeANY, Qclass: dns.ClassINET})...)
+	}
+	if len(rrs) == 0 {
+		return
+	}
+
+	resp := new(dns.Msg)
+	resp.Response = true
+	resp.Authoritative = true
+	resp.Answer = rrs
+	s.c.sendQuery(resp)
+}
+
+// sendGoodbye multicasts records (expected to already carry Ttl=0) so peers
+// purge them from their caches immediately instead of waiting out the
+// original TTL.
+func (s *server) sendGoodbye(records []dns.RR) {
+	if len(records) == 0 {
+		return
+	}
+
+	resp := new(dns.Msg)
+	resp.Response = true
+	resp.Authoritative = true
+	resp.Answer = records
+	s.c.sendQuery(resp)
+}
+
+// shutdown stops answering queries and releases the underlying sockets.
+func (s *server) shutdown() error {
+	s.cancel()
+	s.c.shutdown()
+	return nil
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, s := range haystack {
+		if s == needle {
+			return true
+		}
+	}
+	return false
+}
+
+// Server represents a long-lived mDNS registration of a single
+// ServiceEntry, as returned by Register/RegisterProxy. It is a thin
+// caller-facing wrapper over the shared server/probe/announce machinery.
+type Server struct {
+	entry *ServiceEntry
+	srv   *server
+}
+
+// Shutdown sends a goodbye packet for the registered entry and stops
+// answering queries for it.
+func (s *Server) Shutdown() {
+	gb := s.entry.records()
+	for _, rr := range gb {
+		rr.Header().Ttl = 0
+	}
+	s.srv.sendGoodbye(gb)
+	s.srv.shutdown()
+}
+
+// records builds the PTR/SRV/TXT/A/AAAA record set this entry answers for.
+func (e *ServiceEntry) records() []dns.RR {
+	instance := e.ServiceInstanceName()
+	host := e.HostName
+	if host == "" {
+		host = instance
+	}
+
+	rrs := []dns.RR{
+		&dns.PTR{
+			Hdr: dns.RR_Header{Name: e.ServiceName(), Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: defaultTTL},
+			Ptr: instance,
+		},
+		&dns.SRV{
+			Hdr:      dns.RR_Header{Name: instance, Rrtype: dns.TypeSRV, Class: qClassCacheFlush | dns.ClassINET, Ttl: defaultTTL},
+			Priority: 0,
+			Weight:   0,
+			Port:     uint16(e.Port),
+			Target:   host,
+		},
+		&dns.TXT{
+			Hdr: dns.RR_Header{Name: instance, Rrtype: dns.TypeTXT, Class: qClassCacheFlush | dns.ClassINET, Ttl: defaultTTL},
+			Txt: e.Text,
+		},
+	}
+
+	for _, ip := range e.AddrIPv4 {
+		rrs = append(rrs, &dns.A{
+			Hdr: dns.RR_Header{Name: host, Rrtype: dns.TypeA, Class: qClassCacheFlush | dns.ClassINET, Ttl: defaultTTL},
+			A:   ip,
+		})
+	}
+	for _, ip := range e.AddrIPv6 {
+		rrs = append(rrs, &dns.AAAA{
+			Hdr:  dns.RR_Header{Name: host, Rrtype: dns.TypeAAAA, Class: qClassCacheFlush | dns.ClassINET, Ttl: defaultTTL},
+			AAAA: ip,
+		})
+	}
+
+	return rrs
+}
+
+// answerFor returns e's records matching q, the way a Server answers
+// incoming queries for its single registered instance.
+func answerFor(e *ServiceEntry, q dns.Question) []dns.RR {
+	var out []dns.RR
+	for _, rr := range e.records() {
+		if rr.Header().Name == q.Name && (q.Qtype == dns.TypeANY || rr.Header().Rrtype == q.Qtype) {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+// Register advertises a service instance on the local network. text is
+// carried verbatim as the TXT record. ifaces selects the interfaces whose
+// addresses are advertised; nil means every up, multicast-capable interface
+// on the host. The responder itself listens and sends on every such
+// interface whatever ifaces holds.
+func Register(instance, service, domain string, port int, text []string, ifaces []net.Interface) (*Server, error) {
+	if domain == "" {
+		domain = "local"
+	}
+
+	entry := NewServiceEntry(instance, service, domain)
+	entry.Port = port
+	entry.Text = text
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("zeroconf: register %s: %w", instance, err)
+	}
+	entry.HostName = host + "."
+	entry.AddrIPv4, entry.AddrIPv6 = hostAddrs(ifaces)
+
+	return registerEntry(entry)
+}
+
+// RegisterProxy advertises a service instance on behalf of a host that is
+// not the local machine, e.g. a device zeroconf cannot run on directly.
+func RegisterProxy(instance, service, domain string, port int, host string, ips []string, text []string, ifaces []net.Interface) (*Server, error) {
+	if domain == "" {
+		domain = "local"
+	}
+
+	entry := NewServiceEntry(instance, service, domain)
+	entry.Port = port
+	entry.Text = text
+	entry.HostName = trimDot(host) + "."
+
+	for _, raw := range ips {
+		ip := net.ParseIP(raw)
+		if ip == nil {
+			continue
+		}
+		if ip.To4() != nil {
+			entry.AddrIPv4 = append(entry.AddrIPv4, ip)
+		} else {
+			entry.AddrIPv6 = append(entry.AddrIPv6, ip)
+		}
+	}
+
+	return registerEntry(entry)
+}
+
+func registerEntry(entry *ServiceEntry) (*Server, error) {
+	srv, err := newServer(func(q dns.Question) []dns.RR { return answerFor(entry, q) })
+	if err != nil {
+		return nil, fmt.Errorf("zeroconf: start server: %w", err)
+	}
+
+	// Only the instance name (owner of SRV/TXT) is exclusively ours; the
+	// service-type PTR is legitimately shared by every instance of this
+	// type, so it is published without a conflict check.
+	if err := srv.probe([]string{entry.ServiceInstanceName()}); err != nil {
+		srv.shutdown()
+		return nil, fmt.Errorf("zeroconf: probe %s: %w", entry.ServiceInstanceName(), err)
+	}
+	srv.publish([]string{entry.ServiceName()})
+	srv.announce()
+
+	return &Server{entry: entry, srv: srv}, nil
+}
+
+func hostAddrs(ifaces []net.Interface) ([]net.IP, []net.IP) {
+	if len(ifaces) == 0 {
+		ifaces = listMulticastInterfaces()
+	}
+
+	var v4, v6 []net.IP
+	for i := range ifaces {
+		a4, a6 := addrsForInterface(&ifaces[i])
+		v4 = append(v4, a4...)
+		v6 = append(v6, a6...)
+	}
+	return v4, v6
+}
@@ -64,8 +64,11 @@ func NewServiceRecord(instance, service, domain string) *ServiceRecord {
 type LookupParams struct {
 	ServiceRecord
 	Entries     chan<- *ServiceEntry // Entries Channel
+	Passive     bool                 // Passive disables outbound queries and known-answer probing
 	stopProbing chan struct{}
 	once        sync.Once
+	doneOnce    sync.Once
+	cache       *entryCache // tracks Added/Updated/Refreshed/Removed across this request's lifetime
 }
 
 // NewLookupParams constructs a LookupParams.
@@ -75,15 +78,33 @@ func NewLookupParams(instance, service, domain string, entries chan<- *ServiceEn
 		Entries:       entries,
 
 		stopProbing: make(chan struct{}),
+		cache:       &entryCache{entries: make(map[string]*ServiceEntry)},
 	}
 }
 
+// NewPassiveLookupParams constructs a LookupParams for passive discovery.
+// The resulting request never transmits periodic queries and never probes
+// for known answers; it only learns about instances from the unsolicited
+// announcements other peers broadcast on their own schedule. This trades
+// discovery latency for bandwidth and power, which suits constrained
+// devices that merely need to learn services already being advertised.
+func NewPassiveLookupParams(service, domain string, entries chan<- *ServiceEntry) *LookupParams {
+	params := NewLookupParams("", service, domain, entries)
+	params.Passive = true
+	params.disableProbing()
+
+	return params
+}
+
 // Notify subscriber that no more entries will arrive. Mostly caused
-// by an expired context.
+// by an expired context. Safe to call more than once or concurrently
+// with a caller-initiated teardown.
 func (l *LookupParams) done() {
-	if l.Entries != nil {
-		close(l.Entries)
-	}
+	l.doneOnce.Do(func() {
+		if l.Entries != nil {
+			close(l.Entries)
+		}
+	})
 }
 
 func (l *LookupParams) disableProbing() {
@@ -94,8 +115,14 @@ func (l *LookupParams) disableProbing() {
 type ServiceEventType string
 
 const (
-	// NewOrUpdated service instance
-	NewOrUpdated ServiceEventType = "NewOrUpdated"
+	// Added notifies when a previously unknown instance is learned
+	Added ServiceEventType = "Added"
+	// Updated notifies when a known instance's port, host, text or
+	// addresses change
+	Updated ServiceEventType = "Updated"
+	// Refreshed notifies when a known instance is re-announced with no
+	// change besides its TTL/expiry, i.e. a cache refresh
+	Refreshed ServiceEventType = "Refreshed"
 	// Removed notifies when an instance is going away
 	Removed ServiceEventType = "Removed"
 )
@@ -124,7 +151,8 @@ func (s *ServiceEntry) EventType() ServiceEventType {
 
 type entryCache struct {
 	sync.RWMutex
-	entries map[string]*ServiceEntry
+	entries     map[string]*ServiceEntry
+	subscribers map[string][]chan ServiceEntry
 }
 
 // Browser is reference to an instance of browser
@@ -0,0 +1,8 @@
+package zeroconf
+
+import "strings"
+
+// trimDot trims the leading/trailing dots from a DNS label or name.
+func trimDot(s string) string {
+	return strings.Trim(s, ".")
+}